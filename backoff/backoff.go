@@ -0,0 +1,107 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+// Package backoff provides an exponential backoff helper shared by
+// the installer, destroyer and pinger subsystems so that retrying
+// against a slow-booting or unreachable server behaves the same way
+// everywhere in the autoscaler.
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	// DefaultInitialInterval is the delay before the second attempt.
+	DefaultInitialInterval = 2 * time.Second
+	// DefaultMaxInterval caps the delay between attempts.
+	DefaultMaxInterval = 60 * time.Second
+	// DefaultMaxAttempts caps the number of attempts before giving up.
+	DefaultMaxAttempts = 0 // 0 means unbounded; rely on MaxWait / ctx instead.
+	// DefaultMaxWait caps the total time spent retrying.
+	DefaultMaxWait = 5 * time.Minute
+)
+
+// Backoff computes exponentially increasing, jittered retry
+// intervals, capped at MaxInterval, and reports when the caller
+// should give up based on MaxAttempts and MaxWait.
+type Backoff struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxAttempts     int
+	MaxWait         time.Duration
+
+	attempt int
+	started time.Time
+}
+
+// New returns a Backoff configured with the package defaults.
+func New() *Backoff {
+	return &Backoff{
+		InitialInterval: DefaultInitialInterval,
+		MaxInterval:     DefaultMaxInterval,
+		MaxAttempts:     DefaultMaxAttempts,
+		MaxWait:         DefaultMaxWait,
+	}
+}
+
+// NewWithLimits returns a Backoff configured with the package
+// defaults, except maxInterval, maxAttempts and maxWait override
+// their respective defaults whenever they are non-zero. This lets
+// callers expose per-pool knobs (e.g. config.Runner.Backoff) without
+// duplicating the package defaults at every call site.
+func NewWithLimits(maxInterval time.Duration, maxAttempts int, maxWait time.Duration) *Backoff {
+	b := New()
+	if maxInterval > 0 {
+		b.MaxInterval = maxInterval
+	}
+	if maxAttempts > 0 {
+		b.MaxAttempts = maxAttempts
+	}
+	if maxWait > 0 {
+		b.MaxWait = maxWait
+	}
+	return b
+}
+
+// Next returns the delay before the next attempt and increments the
+// internal attempt counter. The delay doubles every attempt, up to
+// MaxInterval, with +/-20% jitter applied.
+func (b *Backoff) Next() time.Duration {
+	if b.started.IsZero() {
+		b.started = time.Now()
+	}
+	b.attempt++
+
+	interval := b.InitialInterval << uint(b.attempt-1)
+	if interval <= 0 || interval > b.MaxInterval {
+		interval = b.MaxInterval
+	}
+
+	jitter := time.Duration(float64(interval) * 0.2 * (rand.Float64()*2 - 1))
+	interval += jitter
+	if interval < 0 {
+		interval = 0
+	}
+	return interval
+}
+
+// Attempt returns the number of attempts made so far.
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+// Exhausted reports whether the caller should stop retrying, either
+// because MaxAttempts was reached or MaxWait has elapsed since the
+// first call to Next.
+func (b *Backoff) Exhausted() bool {
+	if b.MaxAttempts > 0 && b.attempt >= b.MaxAttempts {
+		return true
+	}
+	if b.MaxWait > 0 && !b.started.IsZero() && time.Since(b.started) >= b.MaxWait {
+		return true
+	}
+	return false
+}
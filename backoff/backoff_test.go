@@ -0,0 +1,58 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextDoublesUpToMax(t *testing.T) {
+	b := &Backoff{InitialInterval: time.Second, MaxInterval: 4 * time.Second}
+
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		got := b.Next()
+		low, high := want-want/5, want+want/5
+		if got < low || got > high {
+			t.Fatalf("attempt %d: Next() = %v, want within 20%% of %v", i+1, got, want)
+		}
+	}
+}
+
+func TestExhaustedMaxAttempts(t *testing.T) {
+	b := &Backoff{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, MaxAttempts: 2}
+
+	b.Next()
+	if b.Exhausted() {
+		t.Fatalf("Exhausted() = true after 1 attempt, want false")
+	}
+	b.Next()
+	if !b.Exhausted() {
+		t.Fatalf("Exhausted() = false after 2 attempts, want true")
+	}
+}
+
+func TestExhaustedMaxWait(t *testing.T) {
+	b := &Backoff{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, MaxWait: time.Millisecond}
+
+	b.Next()
+	time.Sleep(5 * time.Millisecond)
+	if !b.Exhausted() {
+		t.Fatalf("Exhausted() = false after MaxWait elapsed, want true")
+	}
+}
+
+func TestNewWithLimitsOverridesOnlyNonZero(t *testing.T) {
+	b := NewWithLimits(0, 5, 0)
+	if b.MaxInterval != DefaultMaxInterval {
+		t.Fatalf("MaxInterval = %v, want default %v", b.MaxInterval, DefaultMaxInterval)
+	}
+	if b.MaxAttempts != 5 {
+		t.Fatalf("MaxAttempts = %v, want 5", b.MaxAttempts)
+	}
+	if b.MaxWait != DefaultMaxWait {
+		t.Fatalf("MaxWait = %v, want default %v", b.MaxWait, DefaultMaxWait)
+	}
+}
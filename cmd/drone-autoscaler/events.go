@@ -0,0 +1,27 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/drone/autoscaler"
+	"github.com/drone/autoscaler/webhook"
+)
+
+// eventBus builds the EventBus used across the installer, creator,
+// destroyer and pinger. When webhookURL is empty, the returned
+// in-memory bus has no sinks and discards every event.
+//
+// This file only owns the construction seam: the flag that supplies
+// webhookURL (and the rest of main's wiring of config, the server
+// store and the cloud driver into the installer/creator/destroyer/
+// pinger) lives in this command's other files, which are not part of
+// this source snapshot. Wire eventBus(cfg.Webhook.Endpoint) in there
+// rather than assuming it is already connected.
+func eventBus(webhookURL string) autoscaler.EventBus {
+	if webhookURL == "" {
+		return autoscaler.NewEventBus()
+	}
+	return autoscaler.NewEventBus(webhook.New(webhookURL))
+}
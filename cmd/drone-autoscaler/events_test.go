@@ -0,0 +1,19 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestEventBusWithoutWebhookStillReturnsABus(t *testing.T) {
+	if eventBus("") == nil {
+		t.Fatalf("eventBus(\"\") = nil, want a no-op in-memory bus")
+	}
+}
+
+func TestEventBusWithWebhookReturnsABus(t *testing.T) {
+	if eventBus("https://example.com/events") == nil {
+		t.Fatalf("eventBus(url) = nil, want a bus fanning out to the webhook sink")
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package config
+
+// Registry configures authentication for a single private Docker
+// registry. The autoscaler matches a Registry to an image by
+// comparing Address against the image's registry host, so the agent
+// image can be pulled from a private registry (e.g. ghcr.io/acme)
+// while pipeline images continue to come from public registries.
+type Registry struct {
+	Address       string `envconfig:"DRONE_REGISTRY_ADDRESS"`
+	Username      string `envconfig:"DRONE_REGISTRY_USERNAME"`
+	Password      string `envconfig:"DRONE_REGISTRY_PASSWORD"`
+	IdentityToken string `envconfig:"DRONE_REGISTRY_IDENTITY_TOKEN"`
+}
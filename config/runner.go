@@ -0,0 +1,74 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package config
+
+import "time"
+
+// Runner configures the CI runner agent that the autoscaler installs
+// onto each provisioned server.
+type Runner struct {
+	// Kind selects the RunnerProvisioner implementation used to get
+	// the agent running on a new server. Supported values are
+	// "docker" (default), "exec" and "kube".
+	Kind string `envconfig:"DRONE_RUNNER_KIND" default:"docker"`
+
+	Volumes    string `envconfig:"DRONE_RUNNER_VOLUMES"`
+	Devices    string `envconfig:"DRONE_RUNNER_DEVICES"`
+	Privileged string `envconfig:"DRONE_RUNNER_PRIVILEGED_IMAGES"`
+
+	// Registries authenticates image pulls against one or more
+	// private registries, keyed by matching the registry's address
+	// against the host portion of the image being pulled.
+	Registries []Registry
+
+	// Network is the name of a user-defined Docker network the agent
+	// container is attached to, created if it does not already
+	// exist. Leave empty to use the daemon's default bridge network.
+	Network string `envconfig:"DRONE_RUNNER_NETWORK"`
+
+	// NamedVolumes lists Docker volumes that are created (if
+	// missing) and bind-mounted into the agent container, for
+	// sharing a cache or registry mirror sidecar on the same VM.
+	NamedVolumes []string `envconfig:"DRONE_RUNNER_NAMED_VOLUMES"`
+
+	// Backoff overrides the retry behavior of the connectivity
+	// poller used by the installer, destroyer and pinger. Zero
+	// values fall back to the backoff package's defaults.
+	Backoff Backoff
+
+	Exec RunnerExec
+	Kube RunnerKube
+}
+
+// Backoff configures the exponential backoff used when polling a
+// server for Docker or Kubernetes connectivity.
+type Backoff struct {
+	MaxInterval time.Duration `envconfig:"DRONE_RUNNER_BACKOFF_MAX_INTERVAL"`
+	MaxAttempts int           `envconfig:"DRONE_RUNNER_BACKOFF_MAX_ATTEMPTS"`
+	MaxWait     time.Duration `envconfig:"DRONE_RUNNER_BACKOFF_MAX_WAIT"`
+}
+
+// RunnerExec configures the SSH/systemd RunnerProvisioner, which
+// installs the agent as a systemd-managed binary rather than a
+// Docker container.
+type RunnerExec struct {
+	// BinaryURL, when set, is fetched with curl onto the VM before
+	// the systemd unit is started, so the image does not need to
+	// have drone-runner-exec baked in ahead of time. Leave empty if
+	// the VM image already provides the binary.
+	BinaryURL string `envconfig:"DRONE_RUNNER_EXEC_BINARY_URL"`
+	Unit      string `envconfig:"DRONE_RUNNER_EXEC_UNIT" default:"drone-runner-exec.service"`
+}
+
+// RunnerKube configures the Kubernetes RunnerProvisioner, which
+// waits for the provisioned VM to register itself as a node in an
+// existing cluster and schedules the agent as a Deployment pinned to
+// that node, rather than installing it on the VM directly. It does
+// not join the VM to the cluster itself.
+type RunnerKube struct {
+	Kubeconfig string `envconfig:"DRONE_RUNNER_KUBE_CONFIG"`
+	Namespace  string `envconfig:"DRONE_RUNNER_KUBE_NAMESPACE" default:"drone"`
+	Image      string `envconfig:"DRONE_RUNNER_KUBE_IMAGE"`
+}
@@ -0,0 +1,11 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package config
+
+// Webhook configures forwarding of lifecycle events to an external
+// URL as JSON POSTs.
+type Webhook struct {
+	Endpoint string `envconfig:"DRONE_WEBHOOK_ENDPOINT"`
+}
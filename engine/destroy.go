@@ -0,0 +1,105 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/drone/autoscaler"
+	"github.com/drone/autoscaler/backoff"
+	"github.com/drone/autoscaler/config"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RunnerDeprovisioner removes whatever the matching RunnerProvisioner
+// installed on a server, so the destroyer can clean up agent
+// resources (containers, networks, named volumes, ...) before the
+// underlying VM itself is destroyed.
+type RunnerDeprovisioner interface {
+	Deprovision(ctx context.Context, instance *autoscaler.Server) error
+}
+
+// destroyer drives RunnerDeprovisioner.Deprovision for every server
+// moving into StateStopping, mirroring installer's handling of
+// StateCreated.
+type destroyer struct {
+	wg sync.WaitGroup
+
+	deprovisioner RunnerDeprovisioner
+	servers       autoscaler.ServerStore
+	events        autoscaler.EventBus
+	backoff       config.Backoff
+}
+
+// NewDestroyer returns a destroyer that tears down the agent
+// resources runner's RunnerProvisioner installed, using opts for
+// connectivity to the server being destroyed.
+func NewDestroyer(runner config.Runner, servers autoscaler.ServerStore, opts installerOpts) (*destroyer, error) {
+	deprovisioner, err := newDeprovisioner(runner, opts)
+	if err != nil {
+		return nil, err
+	}
+	events := opts.events
+	if events == nil {
+		events = autoscaler.NewEventBus()
+	}
+	return &destroyer{
+		deprovisioner: deprovisioner,
+		servers:       servers,
+		events:        events,
+		backoff:       runner.Backoff,
+	}, nil
+}
+
+func (d *destroyer) Destroy(ctx context.Context) error {
+	logger := log.Ctx(ctx)
+
+	servers, err := d.servers.ListState(ctx, autoscaler.StateStopping)
+	if err != nil {
+		return err
+	}
+
+	for _, server := range servers {
+		d.wg.Add(1)
+		go func(server *autoscaler.Server) {
+			defer d.wg.Done()
+			if err := d.deprovisionWithRetry(ctx, server); err != nil {
+				logger.Error().
+					Err(err).
+					Str("server", server.Name).
+					Msg("cannot deprovision agent resources")
+			}
+		}(server)
+	}
+	return nil
+}
+
+// deprovisionWithRetry retries a failed Deprovision using the same
+// exponential backoff the installer uses for its connectivity
+// checks, so a server that is briefly unreachable during scale-down
+// doesn't leak its agent container, network or named volumes.
+func (d *destroyer) deprovisionWithRetry(ctx context.Context, server *autoscaler.Server) error {
+	retry := backoff.NewWithLimits(d.backoff.MaxInterval, d.backoff.MaxAttempts, d.backoff.MaxWait)
+
+	var err error
+	interval := time.Duration(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+			if err = d.deprovisioner.Deprovision(ctx, server); err == nil {
+				return nil
+			}
+			interval = retry.Next()
+			if retry.Exhausted() {
+				return err
+			}
+		}
+	}
+}
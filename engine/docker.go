@@ -0,0 +1,328 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/drone/autoscaler"
+	"github.com/drone/autoscaler/config"
+
+	"docker.io/go-docker/api/types"
+	"docker.io/go-docker/api/types/container"
+	"docker.io/go-docker/api/types/network"
+	"docker.io/go-docker/api/types/volume"
+	"github.com/rs/zerolog/log"
+
+	"github.com/drone/autoscaler/backoff"
+)
+
+// DockerAPIClient is the subset of the Docker client used by the
+// docker RunnerProvisioner, satisfied both by the plain client
+// returned from clientFunc and by the mTLS client built from dockerTLS.
+type DockerAPIClient interface {
+	ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error)
+	ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) (container.ContainerCreateCreatedBody, error)
+	ContainerStart(ctx context.Context, containerID string, options types.ContainerStartOptions) error
+	ContainerRemove(ctx context.Context, containerID string, options types.ContainerRemoveOptions) error
+
+	NetworkInspect(ctx context.Context, networkID string, options types.NetworkInspectOptions) (types.NetworkResource, error)
+	NetworkCreate(ctx context.Context, name string, options types.NetworkCreate) (types.NetworkCreateResponse, error)
+	NetworkRemove(ctx context.Context, networkID string) error
+
+	VolumeInspect(ctx context.Context, volumeID string) (types.Volume, error)
+	VolumeCreate(ctx context.Context, options volume.VolumesCreateBody) (types.Volume, error)
+	VolumeRemove(ctx context.Context, volumeID string, force bool) error
+}
+
+// dockerProvisioner is the default RunnerProvisioner. It installs the
+// Drone Docker agent by pulling the configured image, creating a
+// container wired up with DRONE_RPC_* environment variables, and
+// starting it.
+type dockerProvisioner struct {
+	installerOpts
+	runner config.Runner
+
+	// dockerClient returns a Docker client for instance. It defaults
+	// to dialDockerClient; tests substitute a fake to exercise
+	// Provision/Deprovision without a live daemon.
+	dockerClient func(instance *autoscaler.Server) (DockerAPIClient, error)
+}
+
+func newDockerProvisioner(runner config.Runner, opts installerOpts) *dockerProvisioner {
+	p := &dockerProvisioner{installerOpts: opts, runner: runner}
+	p.dockerClient = p.dialDockerClient
+	return p
+}
+
+// dialDockerClient is the default value of the dockerClient field,
+// dialing over mTLS when the pool has client certificate material
+// configured and falling back to the plain clientFunc otherwise.
+func (p *dockerProvisioner) dialDockerClient(instance *autoscaler.Server) (DockerAPIClient, error) {
+	if p.tls != nil {
+		return p.tls.dial(instance)
+	}
+	return p.client(instance)
+}
+
+func (p *dockerProvisioner) Provision(ctx context.Context, instance *autoscaler.Server) error {
+	logger := log.Ctx(ctx).With().
+		Str("ip", instance.Address).
+		Str("name", instance.Name).
+		Logger()
+
+	client, err := p.dockerClient(instance)
+	if err != nil {
+		logger.Error().Err(err).
+			Msg("cannot create docker client")
+		return err
+	}
+
+	debug := logger.Debug().
+		Str("name", instance.Name).
+		Bool("tls", p.tls != nil)
+	if p.tls != nil {
+		debug = debug.Str("tls-fingerprint", p.tls.fingerprint())
+	}
+	debug.Msg("check docker connectivity")
+
+	phaseStart := time.Now()
+	retry := backoff.NewWithLimits(p.runner.Backoff.MaxInterval, p.runner.Backoff.MaxAttempts, p.runner.Backoff.MaxWait)
+	interval := time.Duration(0)
+poller:
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Debug().
+				Str("name", instance.Name).
+				Msg("connection timeout")
+			return dockerUnreachableError(ctx.Err())
+		case <-time.After(interval):
+			_, err := client.ContainerList(ctx, types.ContainerListOptions{})
+			if err != nil {
+				interval = retry.Next()
+				logger.Debug().
+					Err(err).
+					Int("attempt", retry.Attempt()).
+					Str("name", instance.Name).
+					Msgf("cannot connect, retry in %v", interval)
+
+				if retry.Exhausted() {
+					return dockerUnreachableError(err)
+				}
+				continue
+			}
+			break poller
+		}
+	}
+	p.publish(ctx, autoscaler.EventDockerReachable, instance, phaseStart)
+
+	logger.Debug().
+		Str("image", p.image).
+		Msg("pull docker image")
+
+	phaseStart = time.Now()
+	pullOpts := types.ImagePullOptions{}
+	if auth, ok := registryAuth(p.runner.Registries, p.image); ok {
+		pullOpts.RegistryAuth = auth
+	}
+
+	rc, err := client.ImagePull(ctx, p.image, pullOpts)
+	if err != nil {
+		logger.Error().Err(err).
+			Str("image", p.image).
+			Msg("cannot pull docker image")
+		return err
+	}
+	io.Copy(ioutil.Discard, rc)
+	rc.Close()
+	p.publish(ctx, autoscaler.EventImagePulled, instance, phaseStart)
+
+	var netConfig *network.NetworkingConfig
+	if p.runner.Network != "" {
+		if err := ensureNetwork(ctx, client, p.runner.Network); err != nil {
+			logger.Error().Err(err).
+				Str("network", p.runner.Network).
+				Msg("cannot create docker network")
+			return err
+		}
+		netConfig = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				p.runner.Network: {},
+			},
+		}
+	}
+
+	// binds is built per-call rather than mutated on p.volumes:
+	// Provision runs concurrently, once per server, against the same
+	// *dockerProvisioner, so appending to a shared slice would race
+	// and leak one server's named-volume binds onto another's.
+	binds := append([]string(nil), p.volumes...)
+	for _, name := range p.runner.NamedVolumes {
+		if err := ensureVolume(ctx, client, name); err != nil {
+			logger.Error().Err(err).
+				Str("volume", name).
+				Msg("cannot create docker volume")
+			return err
+		}
+		binds = append(binds, fmt.Sprintf("%s:/%s", name, name))
+	}
+	binds = append(binds, "/var/run/docker.sock:/var/run/docker.sock")
+
+	logger.Debug().
+		Str("image", p.image).
+		Msg("create agent container")
+
+	phaseStart = time.Now()
+	res, err := client.ContainerCreate(ctx,
+		&container.Config{
+			Image:        p.image,
+			AttachStdout: true,
+			AttachStderr: true,
+			Env: []string{
+				fmt.Sprintf("DRONE_RPC_SERVER=%s://%s", p.proto, p.host),
+				fmt.Sprintf("DRONE_RPC_SECRET=%s", p.secret),
+				fmt.Sprintf("DRONE_RUNNER_CAPACITY=%v", instance.Capacity),
+				fmt.Sprintf("DRONE_RUNNER_NAME=%s", instance.Name),
+				fmt.Sprintf("DRONE_RUNNER_VOLUMES=%s", p.runner.Volumes),
+				fmt.Sprintf("DRONE_RUNNER_DEVICES=%s", p.runner.Devices),
+				fmt.Sprintf("DRONE_RUNNER_PRIVILEGED_IMAGES=%s", p.runner.Privileged),
+				fmt.Sprintf("DRONE_RUNNER_REGISTRY_AUTH=%s", allRegistryAuth(p.runner.Registries)),
+			},
+			Volumes: toVol(binds),
+			Labels: map[string]string{
+				"com.centurylinklabs.watchtower.enable":      "true",
+				"com.centurylinklabs.watchtower.stop-signal": "SIGHUP",
+				"io.drone.agent.name":                        instance.Name,
+				"io.drone.agent.zone":                        instance.Region,
+				"io.drone.agent.size":                        instance.Size,
+				"io.drone.agent.instance":                    instance.ID,
+				"io.drone.agent.capacity":                    fmt.Sprint(instance.Capacity),
+			},
+		},
+		&container.HostConfig{
+			Binds: binds,
+			RestartPolicy: container.RestartPolicy{
+				Name: "always",
+			},
+		}, netConfig, "agent")
+
+	if err != nil {
+		logger.Error().Err(err).
+			Str("image", p.image).
+			Msg("cannot create agent container")
+		return err
+	}
+	p.publish(ctx, autoscaler.EventContainerCreate, instance, phaseStart)
+
+	logger.Debug().
+		Str("image", p.image).
+		Msg("start the agent container")
+
+	phaseStart = time.Now()
+	err = client.ContainerStart(ctx, res.ID, types.ContainerStartOptions{})
+	if err != nil {
+		logger.Debug().
+			Str("image", p.image).
+			Msg("cannot start the agent container")
+		return err
+	}
+	p.publish(ctx, autoscaler.EventContainerStart, instance, phaseStart)
+
+	logger.Debug().
+		Str("image", p.image).
+		Msg("agent container started")
+
+	return nil
+}
+
+// Deprovision removes the agent container and the network/named
+// volumes Provision created for instance, so scale-down does not
+// leak Docker resources on a VM that is about to be destroyed.
+func (p *dockerProvisioner) Deprovision(ctx context.Context, instance *autoscaler.Server) error {
+	logger := log.Ctx(ctx).With().
+		Str("ip", instance.Address).
+		Str("name", instance.Name).
+		Logger()
+
+	client, err := p.dockerClient(instance)
+	if err != nil {
+		logger.Error().Err(err).
+			Msg("cannot create docker client")
+		return err
+	}
+
+	logger.Debug().Msg("remove agent container")
+	err = client.ContainerRemove(ctx, "agent", types.ContainerRemoveOptions{Force: true})
+	if err != nil {
+		logger.Error().Err(err).
+			Msg("cannot remove agent container")
+		return err
+	}
+
+	logger.Debug().Msg("remove runner network and named volumes")
+	if err := TeardownResources(ctx, client, p.runner); err != nil {
+		logger.Error().Err(err).
+			Msg("cannot remove runner network and named volumes")
+		return err
+	}
+
+	return nil
+}
+
+// publish emits a lifecycle event for instance, stamping it with the
+// time elapsed since phaseStart.
+func (p *dockerProvisioner) publish(ctx context.Context, kind autoscaler.EventKind, instance *autoscaler.Server, phaseStart time.Time) {
+	p.events.Publish(ctx, autoscaler.Event{
+		Kind:     kind,
+		Server:   instance,
+		Duration: time.Since(phaseStart),
+	})
+}
+
+// helper function that converts a slice of volume paths to a set of
+// unique volume names.
+func toVol(paths []string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, path := range paths {
+		parts, err := splitVolumeParts(path)
+		if err != nil {
+			continue
+		}
+		if len(parts) < 2 {
+			continue
+		}
+		set[parts[1]] = struct{}{}
+	}
+	return set
+}
+
+// helper function that split volume path
+func splitVolumeParts(volumeParts string) ([]string, error) {
+	pattern := `^((?:[\w]\:)?[^\:]*)\:((?:[\w]\:)?[^\:]*)(?:\:([rwom]*))?`
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return []string{}, err
+	}
+	if r.MatchString(volumeParts) {
+		results := r.FindStringSubmatch(volumeParts)[1:]
+		cleanResults := []string{}
+		for _, item := range results {
+			if item != "" {
+				cleanResults = append(cleanResults, item)
+			}
+		}
+		return cleanResults, nil
+	} else {
+		return strings.Split(volumeParts, ":"), nil
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/drone/autoscaler"
+	"github.com/drone/autoscaler/config"
+)
+
+func newFakeDockerProvisioner(client *fakeDockerClient, dialErr error) *dockerProvisioner {
+	p := newDockerProvisioner(config.Runner{}, installerOpts{events: autoscaler.NewEventBus()})
+	p.dockerClient = func(instance *autoscaler.Server) (DockerAPIClient, error) {
+		if dialErr != nil {
+			return nil, dialErr
+		}
+		return client, nil
+	}
+	return p
+}
+
+func TestDockerProvisionerProvisionCreatesAndStartsContainer(t *testing.T) {
+	client := &fakeDockerClient{}
+	p := newFakeDockerProvisioner(client, nil)
+
+	err := p.Provision(context.Background(), &autoscaler.Server{Name: "agent-1", Address: "10.0.0.5"})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if client.createdContainer != "agent" {
+		t.Fatalf("Provision() created container %q, want agent", client.createdContainer)
+	}
+	if !client.started {
+		t.Fatalf("Provision() did not start the agent container")
+	}
+}
+
+func TestDockerProvisionerProvisionReturnsDockerClientError(t *testing.T) {
+	p := newFakeDockerProvisioner(nil, errors.New("no route to host"))
+
+	if err := p.Provision(context.Background(), &autoscaler.Server{Name: "agent-1", Address: "10.0.0.5"}); err == nil {
+		t.Fatalf("Provision() error = nil, want docker client error")
+	}
+}
+
+func TestDockerProvisionerDeprovisionRemovesContainerAndResources(t *testing.T) {
+	client := &fakeDockerClient{}
+	p := newFakeDockerProvisioner(client, nil)
+	p.runner = config.Runner{Network: "ci-net", NamedVolumes: []string{"cache"}}
+
+	if err := p.Deprovision(context.Background(), &autoscaler.Server{Name: "agent-1", Address: "10.0.0.5"}); err != nil {
+		t.Fatalf("Deprovision() error = %v", err)
+	}
+
+	if client.removedNetwork != "ci-net" {
+		t.Fatalf("Deprovision() removedNetwork = %q, want ci-net", client.removedNetwork)
+	}
+	if len(client.removedVolumes) != 1 || client.removedVolumes[0] != "cache" {
+		t.Fatalf("Deprovision() removedVolumes = %v, want [cache]", client.removedVolumes)
+	}
+}
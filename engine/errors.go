@@ -0,0 +1,42 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// reasonDockerUnreachable is the structured reason recorded on a
+// server's error state when the docker RunnerProvisioner gives up
+// trying to reach its Docker daemon during install.
+const reasonDockerUnreachable = "docker-unreachable"
+
+// reasonedError carries a structured, machine-readable reason
+// alongside the underlying error, so callers further up the stack
+// (and event subscribers) can distinguish failure modes without
+// parsing free-form text.
+type reasonedError struct {
+	reason string
+	err    error
+}
+
+func (e *reasonedError) Error() string { return fmt.Sprintf("%s: %v", e.reason, e.err) }
+func (e *reasonedError) Unwrap() error { return e.err }
+
+// dockerUnreachableError wraps err with the docker-unreachable reason.
+func dockerUnreachableError(err error) error {
+	return &reasonedError{reason: reasonDockerUnreachable, err: err}
+}
+
+// reasonFor extracts the structured reason from err, falling back to
+// a generic reason for errors not built with a reasonedError.
+func reasonFor(err error) string {
+	var re *reasonedError
+	if errors.As(err, &re) {
+		return re.reason
+	}
+	return "install-failed"
+}
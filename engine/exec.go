@@ -0,0 +1,162 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drone/autoscaler"
+	"github.com/drone/autoscaler/config"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// binaryPath is where the drone-runner-exec binary is expected to
+// live on a provisioned VM, both for the systemd unit's ExecStart and
+// for the curl fetch Provision runs when runner.Exec.BinaryURL is set.
+const binaryPath = "/usr/local/bin/drone-runner-exec"
+
+// execProvisioner installs the agent directly on the VM, with no
+// Docker dependency: it writes a systemd unit file and starts
+// drone-runner-exec as a managed service over SSH.
+type execProvisioner struct {
+	installerOpts
+	runner config.Runner
+
+	// dial opens an SSH session to instance. It defaults to dialSSH,
+	// authenticated with the pool's configured private key; tests
+	// substitute a fake to exercise Provision/Deprovision without a
+	// live VM.
+	dial func(ctx context.Context, instance *autoscaler.Server) (execSession, error)
+}
+
+func newExecProvisioner(runner config.Runner, opts installerOpts) *execProvisioner {
+	p := &execProvisioner{installerOpts: opts, runner: runner}
+	p.dial = p.dialSSH
+	return p
+}
+
+func (p *execProvisioner) Provision(ctx context.Context, instance *autoscaler.Server) error {
+	logger := log.Ctx(ctx).With().
+		Str("ip", instance.Address).
+		Str("name", instance.Name).
+		Logger()
+
+	sess, err := p.dial(ctx, instance)
+	if err != nil {
+		logger.Error().Err(err).
+			Msg("cannot establish ssh connection")
+		return err
+	}
+	defer sess.Close()
+
+	if p.runner.Exec.BinaryURL != "" {
+		logger.Debug().
+			Str("url", p.runner.Exec.BinaryURL).
+			Msg("fetching runner binary")
+
+		cmd := fmt.Sprintf("sudo curl -fsSL %s -o %s && sudo chmod +x %s", p.runner.Exec.BinaryURL, binaryPath, binaryPath)
+		if err := sess.run(ctx, cmd); err != nil {
+			logger.Error().Err(err).
+				Msg("cannot fetch runner binary")
+			return err
+		}
+	}
+
+	logger.Debug().
+		Str("unit", p.runner.Exec.Unit).
+		Msg("writing systemd unit")
+
+	if err := sess.run(ctx, fmt.Sprintf("cat <<'EOF' | sudo tee /etc/systemd/system/%s\n%s\nEOF", p.runner.Exec.Unit, p.unitFile(instance))); err != nil {
+		logger.Error().Err(err).
+			Msg("cannot write systemd unit")
+		return err
+	}
+
+	logger.Debug().
+		Str("unit", p.runner.Exec.Unit).
+		Msg("starting runner service")
+
+	cmd := fmt.Sprintf("sudo systemctl daemon-reload && sudo systemctl enable --now %s", p.runner.Exec.Unit)
+	if err := sess.run(ctx, cmd); err != nil {
+		logger.Error().Err(err).
+			Msg("cannot start runner service")
+		return err
+	}
+
+	logger.Debug().
+		Str("unit", p.runner.Exec.Unit).
+		Msg("runner service started")
+
+	return nil
+}
+
+// Deprovision stops and disables the runner unit. There is no
+// network/volume cleanup here: the exec provisioner never creates
+// Docker resources, so there is nothing else to tear down before the
+// VM itself is destroyed.
+func (p *execProvisioner) Deprovision(ctx context.Context, instance *autoscaler.Server) error {
+	logger := log.Ctx(ctx).With().
+		Str("ip", instance.Address).
+		Str("name", instance.Name).
+		Logger()
+
+	sess, err := p.dial(ctx, instance)
+	if err != nil {
+		logger.Error().Err(err).
+			Msg("cannot establish ssh connection")
+		return err
+	}
+	defer sess.Close()
+
+	cmd := fmt.Sprintf("sudo systemctl disable --now %s", p.runner.Exec.Unit)
+	if err := sess.run(ctx, cmd); err != nil {
+		logger.Error().Err(err).
+			Msg("cannot stop runner service")
+		return err
+	}
+	return nil
+}
+
+// unitFile renders the systemd unit that runs drone-runner-exec with
+// DRONE_RPC_SERVER, DRONE_RPC_SECRET, DRONE_RUNNER_CAPACITY and
+// DRONE_RUNNER_NAME set as the Docker provisioner sets them. The
+// volume, device, privileged-image and registry-auth settings the
+// Docker provisioner also passes are not set in the unit.
+func (p *execProvisioner) unitFile(instance *autoscaler.Server) string {
+	return fmt.Sprintf(`[Unit]
+Description=drone runner exec
+After=network.target
+
+[Service]
+ExecStart=%s
+Restart=always
+Environment=DRONE_RPC_SERVER=%s://%s
+Environment=DRONE_RPC_SECRET=%s
+Environment=DRONE_RUNNER_CAPACITY=%v
+Environment=DRONE_RUNNER_NAME=%s
+
+[Install]
+WantedBy=multi-user.target
+`, binaryPath, p.proto, p.host, p.secret, instance.Capacity, instance.Name)
+}
+
+// dialSSH is the default value of the dial field, authenticating with
+// the pool's configured private key.
+func (p *execProvisioner) dialSSH(ctx context.Context, instance *autoscaler.Server) (execSession, error) {
+	signer, err := ssh.ParsePrivateKey(p.sshKey)
+	if err != nil {
+		return nil, fmt.Errorf("engine: cannot parse ssh private key: %w", err)
+	}
+	return dialSSH(ctx, instance, signer, p.hostKeys)
+}
+
+// execSession runs shell commands on a provisioned server.
+type execSession interface {
+	run(ctx context.Context, cmd string) error
+	Close() error
+}
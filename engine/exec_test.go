@@ -0,0 +1,103 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/drone/autoscaler"
+	"github.com/drone/autoscaler/config"
+)
+
+// fakeExecSession is an execSession double recording every command run
+// against it, so execProvisioner can be tested without a live VM.
+type fakeExecSession struct {
+	commands []string
+	runErr   error
+	closed   bool
+}
+
+func (s *fakeExecSession) run(ctx context.Context, cmd string) error {
+	s.commands = append(s.commands, cmd)
+	return s.runErr
+}
+
+func (s *fakeExecSession) Close() error {
+	s.closed = true
+	return nil
+}
+
+func newFakeExecProvisioner(session *fakeExecSession, dialErr error) *execProvisioner {
+	p := newExecProvisioner(config.Runner{Exec: config.RunnerExec{Unit: "drone-runner-exec.service"}}, installerOpts{})
+	p.dial = func(ctx context.Context, instance *autoscaler.Server) (execSession, error) {
+		if dialErr != nil {
+			return nil, dialErr
+		}
+		return session, nil
+	}
+	return p
+}
+
+func TestExecProvisionerProvisionWritesAndStartsUnit(t *testing.T) {
+	session := &fakeExecSession{}
+	p := newFakeExecProvisioner(session, nil)
+
+	if err := p.Provision(context.Background(), &autoscaler.Server{Name: "agent-1"}); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+
+	if !session.closed {
+		t.Fatalf("Provision() did not close the ssh session")
+	}
+	if len(session.commands) != 2 {
+		t.Fatalf("Provision() ran %d commands, want 2 (write unit, start service)", len(session.commands))
+	}
+	if want := "sudo systemctl daemon-reload && sudo systemctl enable --now drone-runner-exec.service"; session.commands[1] != want {
+		t.Fatalf("Provision() second command = %q, want %q", session.commands[1], want)
+	}
+}
+
+func TestExecProvisionerProvisionFetchesBinaryWhenURLConfigured(t *testing.T) {
+	session := &fakeExecSession{}
+	p := newFakeExecProvisioner(session, nil)
+	p.runner.Exec.BinaryURL = "https://example.com/drone-runner-exec"
+
+	if err := p.Provision(context.Background(), &autoscaler.Server{Name: "agent-1"}); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+
+	if len(session.commands) != 3 {
+		t.Fatalf("Provision() ran %d commands, want 3 (fetch binary, write unit, start service)", len(session.commands))
+	}
+	if want := "sudo curl -fsSL https://example.com/drone-runner-exec -o /usr/local/bin/drone-runner-exec && sudo chmod +x /usr/local/bin/drone-runner-exec"; session.commands[0] != want {
+		t.Fatalf("Provision() first command = %q, want %q", session.commands[0], want)
+	}
+}
+
+func TestExecProvisionerProvisionReturnsDialError(t *testing.T) {
+	p := newFakeExecProvisioner(nil, errors.New("connection refused"))
+
+	if err := p.Provision(context.Background(), &autoscaler.Server{Name: "agent-1"}); err == nil {
+		t.Fatalf("Provision() error = nil, want dial error")
+	}
+}
+
+func TestExecProvisionerDeprovisionStopsUnit(t *testing.T) {
+	session := &fakeExecSession{}
+	p := newFakeExecProvisioner(session, nil)
+
+	if err := p.Deprovision(context.Background(), &autoscaler.Server{Name: "agent-1"}); err != nil {
+		t.Fatalf("Deprovision() error = %v", err)
+	}
+
+	if !session.closed {
+		t.Fatalf("Deprovision() did not close the ssh session")
+	}
+	if len(session.commands) != 1 || session.commands[0] != "sudo systemctl disable --now drone-runner-exec.service" {
+		t.Fatalf("Deprovision() commands = %v, want [sudo systemctl disable --now drone-runner-exec.service]", session.commands)
+	}
+}
@@ -6,25 +6,18 @@ package engine
 
 import (
 	"context"
-	"fmt"
-	"github.com/drone/autoscaler/config"
-	"io"
-	"io/ioutil"
 	"sync"
 	"time"
-	"regexp"
-	"strings"
 
 	"github.com/drone/autoscaler"
+	"github.com/drone/autoscaler/config"
 
-	"docker.io/go-docker/api/types"
-	"docker.io/go-docker/api/types/container"
 	"github.com/rs/zerolog/log"
 )
 
-type installer struct {
-	wg sync.WaitGroup
-
+// installerOpts carries the connection details and agent settings
+// that are common to every RunnerProvisioner implementation.
+type installerOpts struct {
 	image            string
 	secret           string
 	volumes          []string
@@ -32,10 +25,75 @@ type installer struct {
 	proto            string
 	keepaliveTime    time.Duration
 	keepaliveTimeout time.Duration
-	runner 	         config.Runner
 
-	servers autoscaler.ServerStore
-	client  clientFunc
+	client clientFunc
+
+	// tls holds the CA and client certificate used to dial the
+	// remote Docker daemon over mTLS. It is nil when the pool has
+	// no TLS material configured, in which case client is used to
+	// obtain an unauthenticated Docker client instead.
+	tls *dockerTLS
+
+	// sshKey is the PEM-encoded private key the exec RunnerProvisioner
+	// authenticates with. It is nil unless runner.kind is "exec".
+	sshKey []byte
+
+	// hostKeys pins the SSH host key fingerprint presented by each
+	// server the exec RunnerProvisioner dials. It is shared by every
+	// provisioner built from the same installerOpts. NewInstaller
+	// creates a fresh store, so its pins are not seen by a destroyer,
+	// whose installerOpts come from NewDestroyer's caller.
+	hostKeys *hostKeyStore
+
+	// events receives lifecycle transitions as the provisioner
+	// works through a server. It is never nil; NewInstaller defaults
+	// it to a no-op bus when the caller does not supply one.
+	events autoscaler.EventBus
+}
+
+type installer struct {
+	wg sync.WaitGroup
+
+	provisioner RunnerProvisioner
+	servers     autoscaler.ServerStore
+	events      autoscaler.EventBus
+}
+
+// NewInstaller returns an installer that provisions the runner agent
+// configured by runner, using opts for connectivity to created
+// servers. events may be nil, in which case lifecycle transitions are
+// not published anywhere.
+func NewInstaller(runner config.Runner, servers autoscaler.ServerStore, client clientFunc, image, secret, host, proto string, volumes []string, keepaliveTime, keepaliveTimeout time.Duration, tlsCA, tlsCert, tlsKey, sshKey []byte, events autoscaler.EventBus) (*installer, error) {
+	if events == nil {
+		events = autoscaler.NewEventBus()
+	}
+
+	opts := installerOpts{
+		image:            image,
+		secret:           secret,
+		volumes:          volumes,
+		host:             host,
+		proto:            proto,
+		keepaliveTime:    keepaliveTime,
+		keepaliveTimeout: keepaliveTimeout,
+		client:           client,
+		sshKey:           sshKey,
+		hostKeys:         newHostKeyStore(),
+		events:           events,
+	}
+	if len(tlsCert) != 0 {
+		opts.tls = &dockerTLS{ca: tlsCA, cert: tlsCert, key: tlsKey}
+	}
+
+	provisioner, err := newProvisioner(runner, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &installer{
+		provisioner: provisioner,
+		servers:     servers,
+		events:      events,
+	}, nil
 }
 
 func (i *installer) Install(ctx context.Context) error {
@@ -57,6 +115,7 @@ func (i *installer) Install(ctx context.Context) error {
 				Msg("failed to update server state")
 			return err
 		}
+		i.events.Publish(ctx, autoscaler.Event{Kind: autoscaler.EventServerStaging, Server: server})
 
 		i.wg.Add(1)
 		go func(server *autoscaler.Server) {
@@ -73,119 +132,23 @@ func (i *installer) install(ctx context.Context, instance *autoscaler.Server) er
 		Str("name", instance.Name).
 		Logger()
 
-	client, err := i.client(instance)
-	if err != nil {
+	start := time.Now()
+	if err := i.provisioner.Provision(ctx, instance); err != nil {
 		logger.Error().Err(err).
-			Msg("cannot create docker client")
+			Msg("cannot provision runner")
+		i.events.Publish(ctx, autoscaler.Event{
+			Kind:     autoscaler.EventInstallFailed,
+			Server:   instance,
+			Duration: time.Since(start),
+			Reason:   reasonFor(err),
+			Err:      err,
+		})
 		return i.errorUpdate(ctx, instance, err)
 	}
 
 	logger.Debug().
 		Str("name", instance.Name).
-		Msg("check docker connectivity")
-
-	interval := time.Duration(0)
-poller:
-	for {
-		select {
-		case <-ctx.Done():
-			logger.Debug().
-				Str("name", instance.Name).
-				Msg("connection timeout")
-
-			return i.errorUpdate(ctx, instance, ctx.Err())
-		case <-time.After(interval):
-			interval = time.Minute
-
-			logger.Debug().
-				Str("name", instance.Name).
-				Msg("connecting to docker")
-
-			_, err := client.ContainerList(ctx, types.ContainerListOptions{})
-			if err != nil {
-				logger.Debug().
-					Str("error", err.Error()).
-					Str("name", instance.Name).
-					Msgf("cannot connect, retry in %v", interval)
-				continue
-			}
-			break poller
-		}
-	}
-
-	logger.Debug().
-		Str("image", i.image).
-		Msg("pull docker image")
-
-	rc, err := client.ImagePull(ctx, i.image, types.ImagePullOptions{})
-	if err != nil {
-		logger.Error().Err(err).
-			Str("image", i.image).
-			Msg("cannot pull docker image")
-		return i.errorUpdate(ctx, instance, err)
-	}
-	io.Copy(ioutil.Discard, rc)
-	rc.Close()
-
-	logger.Debug().
-		Str("image", i.image).
-		Msg("create agent container")
-
-	i.volumes = append(i.volumes, "/var/run/docker.sock:/var/run/docker.sock")
-	res, err := client.ContainerCreate(ctx,
-		&container.Config{
-			Image:        i.image,
-			AttachStdout: true,
-			AttachStderr: true,
-			Env: []string{
-				fmt.Sprintf("DRONE_RPC_SERVER=%s://%s", i.proto, i.host),
-				fmt.Sprintf("DRONE_RPC_SECRET=%s", i.secret),
-				fmt.Sprintf("DRONE_RUNNER_CAPACITY=%v", instance.Capacity),
-				fmt.Sprintf("DRONE_RUNNER_NAME=%s", instance.Name),
-				fmt.Sprintf("DRONE_RUNNER_VOLUMES=%s", i.runner.Volumes),
-				fmt.Sprintf("DRONE_RUNNER_DEVICES=%s", i.runner.Devices),
-				fmt.Sprintf("DRONE_RUNNER_PRIVILEGED_IMAGES=%s", i.runner.Privileged),
-			},
-			Volumes: toVol(i.volumes),
-			Labels: map[string]string{
-				"com.centurylinklabs.watchtower.enable":      "true",
-				"com.centurylinklabs.watchtower.stop-signal": "SIGHUP",
-				"io.drone.agent.name":                        instance.Name,
-				"io.drone.agent.zone":                        instance.Region,
-				"io.drone.agent.size":                        instance.Size,
-				"io.drone.agent.instance":                    instance.ID,
-				"io.drone.agent.capacity":                    fmt.Sprint(instance.Capacity),
-			},
-		},
-		&container.HostConfig{
-			Binds: i.volumes,
-			RestartPolicy: container.RestartPolicy{
-				Name: "always",
-			},
-		}, nil, "agent")
-
-	if err != nil {
-		logger.Error().Err(err).
-			Str("image", i.image).
-			Msg("cannot create agent container")
-		return i.errorUpdate(ctx, instance, err)
-	}
-
-	logger.Debug().
-		Str("image", i.image).
-		Msg("start the agent container")
-
-	err = client.ContainerStart(ctx, res.ID, types.ContainerStartOptions{})
-	if err != nil {
-		logger.Debug().
-			Str("image", i.image).
-			Msg("cannot start the agent container")
-		return i.errorUpdate(ctx, instance, err)
-	}
-
-	logger.Debug().
-		Str("image", i.image).
-		Msg("agent container started")
+		Msg("runner agent started")
 
 	instance.State = autoscaler.StateRunning
 	return i.servers.Update(ctx, instance)
@@ -199,41 +162,3 @@ func (i *installer) errorUpdate(ctx context.Context, server *autoscaler.Server,
 	}
 	return err
 }
-
-// helper function that converts a slice of volume paths to a set of
-// unique volume names.
-func toVol(paths []string) map[string]struct{} {
-	set := map[string]struct{}{}
-	for _, path := range paths {
-		parts, err := splitVolumeParts(path)
-		if err != nil {
-			continue
-		}
-		if len(parts) < 2 {
-			continue
-		}
-		set[parts[1]] = struct{}{}
-	}
-	return set
-}
-
-// helper function that split volume path
-func splitVolumeParts(volumeParts string) ([]string, error) {
-	pattern := `^((?:[\w]\:)?[^\:]*)\:((?:[\w]\:)?[^\:]*)(?:\:([rwom]*))?`
-	r, err := regexp.Compile(pattern)
-	if err != nil {
-		return []string{}, err
-	}
-	if r.MatchString(volumeParts) {
-		results := r.FindStringSubmatch(volumeParts)[1:]
-		cleanResults := []string{}
-		for _, item := range results {
-			if item != "" {
-				cleanResults = append(cleanResults, item)
-			}
-		}
-		return cleanResults, nil
-	} else {
-		return strings.Split(volumeParts, ":"), nil
-	}
-}
\ No newline at end of file
@@ -0,0 +1,187 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/drone/autoscaler"
+	"github.com/drone/autoscaler/backoff"
+	"github.com/drone/autoscaler/config"
+
+	"github.com/rs/zerolog/log"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// kubeProvisioner waits for a newly created VM to register itself as
+// a node in an existing Kubernetes cluster, then schedules the runner
+// agent as a Deployment pinned to that node, instead of installing a
+// Docker container directly on the VM.
+//
+// Joining the cluster itself (running kubeadm/kubelet bootstrap on the
+// VM) is not done here: the node is expected to join on its own, the
+// way a cloud-init script baked into the image might configure it.
+// Until that join step is implemented, runner.kind: kube only works
+// against VMs that already bootstrap themselves onto the cluster.
+type kubeProvisioner struct {
+	installerOpts
+	runner config.Runner
+
+	// clientset returns the Kubernetes client used to reach the
+	// cluster. It defaults to buildClientset; tests substitute a fake
+	// to exercise Provision/Deprovision without a live cluster.
+	clientset func() (kubernetes.Interface, error)
+}
+
+func newKubeProvisioner(runner config.Runner, opts installerOpts) *kubeProvisioner {
+	p := &kubeProvisioner{installerOpts: opts, runner: runner}
+	p.clientset = p.buildClientset
+	return p
+}
+
+func (p *kubeProvisioner) Provision(ctx context.Context, instance *autoscaler.Server) error {
+	logger := log.Ctx(ctx).With().
+		Str("ip", instance.Address).
+		Str("name", instance.Name).
+		Logger()
+
+	clientset, err := p.clientset()
+	if err != nil {
+		logger.Error().Err(err).
+			Msg("cannot create kubernetes client")
+		return err
+	}
+
+	logger.Debug().
+		Str("namespace", p.runner.Kube.Namespace).
+		Msg("waiting for node to join cluster")
+
+	if err := p.waitForNode(ctx, clientset, instance); err != nil {
+		logger.Error().Err(err).
+			Msg("node never joined the cluster")
+		return err
+	}
+
+	logger.Debug().
+		Str("namespace", p.runner.Kube.Namespace).
+		Msg("creating runner deployment")
+
+	deployment := p.deployment(instance)
+	_, err = clientset.AppsV1().Deployments(p.runner.Kube.Namespace).Create(ctx, deployment, metav1.CreateOptions{})
+	if err != nil {
+		logger.Error().Err(err).
+			Msg("cannot create runner deployment")
+		return err
+	}
+
+	logger.Debug().
+		Str("namespace", p.runner.Kube.Namespace).
+		Msg("runner deployment created")
+
+	return nil
+}
+
+// Deprovision deletes the runner Deployment scheduled for instance.
+// It does not delete the cluster's Node object for the server, and
+// neither does destroyer, which only calls Deprovision.
+func (p *kubeProvisioner) Deprovision(ctx context.Context, instance *autoscaler.Server) error {
+	logger := log.Ctx(ctx).With().
+		Str("ip", instance.Address).
+		Str("name", instance.Name).
+		Logger()
+
+	clientset, err := p.clientset()
+	if err != nil {
+		logger.Error().Err(err).
+			Msg("cannot create kubernetes client")
+		return err
+	}
+
+	name := fmt.Sprintf("drone-runner-%s", instance.Name)
+	err = clientset.AppsV1().Deployments(p.runner.Kube.Namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil {
+		logger.Error().Err(err).
+			Msg("cannot delete runner deployment")
+		return err
+	}
+	return nil
+}
+
+// buildClientset is the default value of the clientset field, dialing
+// the real cluster named by runner.Kube.Kubeconfig.
+func (p *kubeProvisioner) buildClientset() (kubernetes.Interface, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", p.runner.Kube.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// waitForNode blocks until instance.Name registers itself as a node
+// in the cluster, polling with the same exponential backoff the
+// docker RunnerProvisioner uses for its connectivity check.
+func (p *kubeProvisioner) waitForNode(ctx context.Context, clientset kubernetes.Interface, instance *autoscaler.Server) error {
+	retry := backoff.NewWithLimits(p.runner.Backoff.MaxInterval, p.runner.Backoff.MaxAttempts, p.runner.Backoff.MaxWait)
+	interval := time.Duration(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+			_, err := clientset.CoreV1().Nodes().Get(ctx, instance.Name, metav1.GetOptions{})
+			if err == nil {
+				return nil
+			}
+			interval = retry.Next()
+			if retry.Exhausted() {
+				return err
+			}
+		}
+	}
+}
+
+// deployment renders the runner agent Deployment, pinned to the node
+// that was just provisioned.
+func (p *kubeProvisioner) deployment(instance *autoscaler.Server) *appsv1.Deployment {
+	name := fmt.Sprintf("drone-runner-%s", instance.Name)
+	labels := map[string]string{"io.drone.agent.name": instance.Name}
+	replicas := int32(1)
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: p.runner.Kube.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					NodeName: instance.Name,
+					Containers: []corev1.Container{
+						{
+							Name:  "runner",
+							Image: p.runner.Kube.Image,
+							Env: []corev1.EnvVar{
+								{Name: "DRONE_RPC_SERVER", Value: fmt.Sprintf("%s://%s", p.proto, p.host)},
+								{Name: "DRONE_RPC_SECRET", Value: p.secret},
+								{Name: "DRONE_RUNNER_CAPACITY", Value: fmt.Sprint(instance.Capacity)},
+								{Name: "DRONE_RUNNER_NAME", Value: instance.Name},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/drone/autoscaler"
+	"github.com/drone/autoscaler/config"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newFakeKubeProvisioner(clientset kubernetes.Interface, clientsetErr error) *kubeProvisioner {
+	p := newKubeProvisioner(config.Runner{
+		Kube: config.RunnerKube{Namespace: "drone"},
+		Backoff: config.Backoff{
+			MaxInterval: time.Millisecond,
+			MaxAttempts: 3,
+		},
+	}, installerOpts{})
+	p.clientset = func() (kubernetes.Interface, error) {
+		if clientsetErr != nil {
+			return nil, clientsetErr
+		}
+		return clientset, nil
+	}
+	return p
+}
+
+func TestKubeProvisionerProvisionCreatesDeploymentOnceNodeJoins(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent-1"},
+	})
+	p := newFakeKubeProvisioner(clientset, nil)
+
+	if err := p.Provision(context.Background(), &autoscaler.Server{Name: "agent-1"}); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+
+	_, err := clientset.AppsV1().Deployments("drone").Get(context.Background(), "drone-runner-agent-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Provision() did not create the runner deployment: %v", err)
+	}
+}
+
+func TestKubeProvisionerProvisionFailsIfNodeNeverJoins(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	p := newFakeKubeProvisioner(clientset, nil)
+
+	if err := p.Provision(context.Background(), &autoscaler.Server{Name: "agent-1"}); err == nil {
+		t.Fatalf("Provision() error = nil, want an error when the node never registers")
+	}
+}
+
+func TestKubeProvisionerProvisionReturnsClientsetError(t *testing.T) {
+	p := newFakeKubeProvisioner(nil, errors.New("cannot load kubeconfig"))
+
+	if err := p.Provision(context.Background(), &autoscaler.Server{Name: "agent-1"}); err == nil {
+		t.Fatalf("Provision() error = nil, want clientset error")
+	}
+}
+
+func TestKubeProvisionerDeprovisionDeletesDeployment(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	p := newFakeKubeProvisioner(clientset, nil)
+
+	deployment := p.deployment(&autoscaler.Server{Name: "agent-1"})
+	if _, err := clientset.AppsV1().Deployments("drone").Create(context.Background(), deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("cannot seed deployment: %v", err)
+	}
+
+	if err := p.Deprovision(context.Background(), &autoscaler.Server{Name: "agent-1"}); err != nil {
+		t.Fatalf("Deprovision() error = %v", err)
+	}
+
+	if _, err := clientset.AppsV1().Deployments("drone").Get(context.Background(), "drone-runner-agent-1", metav1.GetOptions{}); err == nil {
+		t.Fatalf("Deprovision() did not delete the runner deployment")
+	}
+}
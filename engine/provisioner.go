@@ -0,0 +1,57 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drone/autoscaler"
+	"github.com/drone/autoscaler/config"
+)
+
+// RunnerProvisioner gets the CI runner agent installed and running on
+// a newly created server. Implementations encapsulate the mechanics
+// of a particular runtime (a Docker container, a systemd unit, a
+// Kubernetes Deployment, ...). The installer is responsible for
+// driving the server's StateStaging -> StateRunning transition; a
+// RunnerProvisioner only needs to report success or failure.
+type RunnerProvisioner interface {
+	// Provision installs and starts the runner agent on instance,
+	// blocking until the agent is confirmed to be running.
+	Provision(ctx context.Context, instance *autoscaler.Server) error
+}
+
+// newProvisioner selects and constructs the RunnerProvisioner
+// configured for runner.Kind.
+func newProvisioner(runner config.Runner, opts installerOpts) (RunnerProvisioner, error) {
+	switch runner.Kind {
+	case "", "docker":
+		return newDockerProvisioner(runner, opts), nil
+	case "exec":
+		return newExecProvisioner(runner, opts), nil
+	case "kube":
+		return newKubeProvisioner(runner, opts), nil
+	default:
+		return nil, fmt.Errorf("engine: unknown runner kind %q", runner.Kind)
+	}
+}
+
+// newDeprovisioner selects and constructs the RunnerDeprovisioner
+// matching runner.Kind, reusing the same constructors as
+// newProvisioner since each provisioner type also knows how to tear
+// down what it installed.
+func newDeprovisioner(runner config.Runner, opts installerOpts) (RunnerDeprovisioner, error) {
+	switch runner.Kind {
+	case "", "docker":
+		return newDockerProvisioner(runner, opts), nil
+	case "exec":
+		return newExecProvisioner(runner, opts), nil
+	case "kube":
+		return newKubeProvisioner(runner, opts), nil
+	default:
+		return nil, fmt.Errorf("engine: unknown runner kind %q", runner.Kind)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/drone/autoscaler/config"
+
+	"docker.io/go-docker/api/types"
+)
+
+// registryAuth finds the Registry configured for image's host, if
+// any, and encodes it the way the Docker API and the
+// DRONE_RUNNER_REGISTRY_AUTH environment variable both expect:
+// base64 of the JSON-marshaled types.AuthConfig.
+func registryAuth(registries []config.Registry, image string) (string, bool) {
+	reg, ok := matchRegistry(registries, image)
+	if !ok {
+		return "", false
+	}
+	return encodeAuth(reg), true
+}
+
+// matchRegistry returns the Registry whose Address matches the host
+// portion of image, so that e.g. ghcr.io/acme/runner is pulled using
+// the ghcr.io credentials while docker.io images remain public.
+func matchRegistry(registries []config.Registry, image string) (config.Registry, bool) {
+	host := imageHost(image)
+	for _, reg := range registries {
+		if reg.Address == host {
+			return reg, true
+		}
+	}
+	return config.Registry{}, false
+}
+
+// imageHost extracts the registry host from a Docker image
+// reference, defaulting to Docker Hub when the image has no explicit
+// registry component.
+func imageHost(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.Contains(parts[0], ".") || strings.Contains(parts[0], ":")) {
+		return parts[0]
+	}
+	return "docker.io"
+}
+
+func encodeAuth(reg config.Registry) string {
+	auth := types.AuthConfig{
+		Username:      reg.Username,
+		Password:      reg.Password,
+		IdentityToken: reg.IdentityToken,
+		ServerAddress: reg.Address,
+	}
+	buf, _ := json.Marshal(auth)
+	return base64.URLEncoding.EncodeToString(buf)
+}
+
+// allRegistryAuth encodes every configured registry so the created
+// container can be given the full credential set via
+// DRONE_RUNNER_REGISTRY_AUTH, letting pipelines scheduled by that
+// runner pull from the same private registries the autoscaler used.
+func allRegistryAuth(registries []config.Registry) string {
+	if len(registries) == 0 {
+		return ""
+	}
+	encoded := make([]string, len(registries))
+	for i, reg := range registries {
+		encoded[i] = encodeAuth(reg)
+	}
+	return strings.Join(encoded, ",")
+}
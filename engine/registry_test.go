@@ -0,0 +1,95 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/drone/autoscaler/config"
+
+	"docker.io/go-docker/api/types"
+)
+
+func TestImageHost(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"drone/agent", "docker.io"},
+		{"drone/agent:1.0", "docker.io"},
+		{"ghcr.io/acme/runner", "ghcr.io"},
+		{"ghcr.io/acme/runner:latest", "ghcr.io"},
+		{"localhost:5000/acme/runner", "localhost:5000"},
+	}
+	for _, test := range tests {
+		if got := imageHost(test.image); got != test.want {
+			t.Errorf("imageHost(%q) = %q, want %q", test.image, got, test.want)
+		}
+	}
+}
+
+func TestMatchRegistry(t *testing.T) {
+	registries := []config.Registry{
+		{Address: "ghcr.io", Username: "acme"},
+		{Address: "docker.io", Username: "hub"},
+	}
+
+	reg, ok := matchRegistry(registries, "ghcr.io/acme/runner")
+	if !ok || reg.Username != "acme" {
+		t.Fatalf("matchRegistry(ghcr.io/acme/runner) = %+v, %v, want ghcr.io entry", reg, ok)
+	}
+
+	if _, ok := matchRegistry(registries, "quay.io/acme/runner"); ok {
+		t.Fatalf("matchRegistry(quay.io/acme/runner) matched, want no match")
+	}
+}
+
+func TestRegistryAuth(t *testing.T) {
+	registries := []config.Registry{
+		{Address: "ghcr.io", Username: "acme", Password: "secret"},
+	}
+
+	auth, ok := registryAuth(registries, "ghcr.io/acme/runner")
+	if !ok {
+		t.Fatalf("registryAuth() ok = false, want true")
+	}
+
+	decoded, err := base64.URLEncoding.DecodeString(auth)
+	if err != nil {
+		t.Fatalf("cannot decode registryAuth() output: %v", err)
+	}
+	var cfg types.AuthConfig
+	if err := json.Unmarshal(decoded, &cfg); err != nil {
+		t.Fatalf("cannot unmarshal registryAuth() output: %v", err)
+	}
+	if cfg.Username != "acme" || cfg.Password != "secret" {
+		t.Fatalf("decoded auth = %+v, want username/password from registry", cfg)
+	}
+
+	if _, ok := registryAuth(registries, "docker.io/library/redis"); ok {
+		t.Fatalf("registryAuth() matched an unconfigured registry")
+	}
+}
+
+func TestAllRegistryAuthEmpty(t *testing.T) {
+	if got := allRegistryAuth(nil); got != "" {
+		t.Fatalf("allRegistryAuth(nil) = %q, want empty string", got)
+	}
+}
+
+func TestAllRegistryAuthEncodesEveryEntry(t *testing.T) {
+	registries := []config.Registry{
+		{Address: "ghcr.io", Username: "one"},
+		{Address: "docker.io", Username: "two"},
+	}
+	auth := allRegistryAuth(registries)
+
+	if parts := strings.Split(auth, ","); len(parts) != 2 {
+		t.Fatalf("allRegistryAuth() encoded %d entries, want 2", len(parts))
+	}
+}
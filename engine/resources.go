@@ -0,0 +1,57 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"context"
+
+	"github.com/drone/autoscaler/config"
+
+	"docker.io/go-docker/api/types"
+	"docker.io/go-docker/api/types/volume"
+)
+
+// ensureNetwork creates the named Docker network if it does not
+// already exist, so the agent container can be attached to it
+// alongside sidecars running on the same VM.
+func ensureNetwork(ctx context.Context, client DockerAPIClient, name string) error {
+	_, err := client.NetworkInspect(ctx, name, types.NetworkInspectOptions{})
+	if err == nil {
+		return nil
+	}
+	_, err = client.NetworkCreate(ctx, name, types.NetworkCreate{})
+	return err
+}
+
+// ensureVolume creates the named Docker volume if it does not already
+// exist, so it can be bind-mounted into the agent container instead
+// of relying solely on host-path binds.
+func ensureVolume(ctx context.Context, client DockerAPIClient, name string) error {
+	_, err := client.VolumeInspect(ctx, name)
+	if err == nil {
+		return nil
+	}
+	_, err = client.VolumeCreate(ctx, volume.VolumesCreateBody{Name: name})
+	return err
+}
+
+// TeardownResources removes the network and named volumes that
+// Provision created for runner, so scale-down does not leak Docker
+// resources on the VM before it is destroyed. It is called by
+// (*dockerProvisioner).Deprovision, which destroyer.Destroy invokes
+// alongside the agent container removal.
+func TeardownResources(ctx context.Context, client DockerAPIClient, runner config.Runner) error {
+	for _, name := range runner.NamedVolumes {
+		if err := client.VolumeRemove(ctx, name, true); err != nil {
+			return err
+		}
+	}
+	if runner.Network != "" {
+		if err := client.NetworkRemove(ctx, runner.Network); err != nil {
+			return err
+		}
+	}
+	return nil
+}
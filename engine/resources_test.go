@@ -0,0 +1,100 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/drone/autoscaler/config"
+
+	"docker.io/go-docker/api/types"
+	"docker.io/go-docker/api/types/container"
+	"docker.io/go-docker/api/types/network"
+	"docker.io/go-docker/api/types/volume"
+)
+
+// fakeDockerClient is a minimal DockerAPIClient double recording the
+// agent container created and started and the volumes and networks
+// removed, so the docker provisioner and TeardownResources can be
+// tested without a real Docker daemon.
+type fakeDockerClient struct {
+	createdContainer string
+	started          bool
+	removedVolumes   []string
+	removedNetwork   string
+}
+
+func (f *fakeDockerClient) ContainerList(context.Context, types.ContainerListOptions) ([]types.Container, error) {
+	return nil, nil
+}
+func (f *fakeDockerClient) ImagePull(context.Context, string, types.ImagePullOptions) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader("")), nil
+}
+func (f *fakeDockerClient) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) (container.ContainerCreateCreatedBody, error) {
+	f.createdContainer = containerName
+	return container.ContainerCreateCreatedBody{}, nil
+}
+func (f *fakeDockerClient) ContainerStart(context.Context, string, types.ContainerStartOptions) error {
+	f.started = true
+	return nil
+}
+func (f *fakeDockerClient) ContainerRemove(context.Context, string, types.ContainerRemoveOptions) error {
+	return nil
+}
+func (f *fakeDockerClient) NetworkInspect(context.Context, string, types.NetworkInspectOptions) (types.NetworkResource, error) {
+	return types.NetworkResource{}, nil
+}
+func (f *fakeDockerClient) NetworkCreate(context.Context, string, types.NetworkCreate) (types.NetworkCreateResponse, error) {
+	return types.NetworkCreateResponse{}, nil
+}
+func (f *fakeDockerClient) NetworkRemove(ctx context.Context, networkID string) error {
+	f.removedNetwork = networkID
+	return nil
+}
+func (f *fakeDockerClient) VolumeInspect(context.Context, string) (types.Volume, error) {
+	return types.Volume{}, nil
+}
+func (f *fakeDockerClient) VolumeCreate(context.Context, volume.VolumesCreateBody) (types.Volume, error) {
+	return types.Volume{}, nil
+}
+func (f *fakeDockerClient) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	f.removedVolumes = append(f.removedVolumes, volumeID)
+	return nil
+}
+
+func TestTeardownResourcesRemovesVolumesAndNetwork(t *testing.T) {
+	client := &fakeDockerClient{}
+	runner := config.Runner{
+		Network:      "ci-net",
+		NamedVolumes: []string{"cache", "mirror"},
+	}
+
+	if err := TeardownResources(context.Background(), client, runner); err != nil {
+		t.Fatalf("TeardownResources() error = %v", err)
+	}
+
+	if len(client.removedVolumes) != 2 || client.removedVolumes[0] != "cache" || client.removedVolumes[1] != "mirror" {
+		t.Fatalf("removedVolumes = %v, want [cache mirror]", client.removedVolumes)
+	}
+	if client.removedNetwork != "ci-net" {
+		t.Fatalf("removedNetwork = %q, want ci-net", client.removedNetwork)
+	}
+}
+
+func TestTeardownResourcesNoNetworkConfigured(t *testing.T) {
+	client := &fakeDockerClient{}
+	runner := config.Runner{NamedVolumes: []string{"cache"}}
+
+	if err := TeardownResources(context.Background(), client, runner); err != nil {
+		t.Fatalf("TeardownResources() error = %v", err)
+	}
+	if client.removedNetwork != "" {
+		t.Fatalf("removedNetwork = %q, want empty when runner.Network is unset", client.removedNetwork)
+	}
+}
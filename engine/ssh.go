@@ -0,0 +1,131 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/drone/autoscaler"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// dialTimeout bounds how long dialSSH's underlying TCP connect may
+// take, independent of ctx, so a VM that never comes up cannot hang
+// the underlying net.Dial forever. The SSH handshake that follows is
+// bounded only by ctx.
+const dialTimeout = 30 * time.Second
+
+// sshSession is the default execSession implementation, backed by a
+// real SSH connection to the provisioned server.
+type sshSession struct {
+	client *ssh.Client
+}
+
+// hostKeyStore pins the SSH host key fingerprint presented by each
+// server address on first connect, for trust-on-first-connect
+// verification across the exec RunnerProvisioner's dial calls.
+//
+// The pins are kept here, on installerOpts, rather than on
+// autoscaler.Server: Server is defined upstream and this source
+// snapshot doesn't carry a field to persist a pinned fingerprint on,
+// so a pin only lives as long as the installer's process instead of
+// surviving a restart. It is safe for concurrent use, since Provision
+// runs once per server on its own goroutine.
+type hostKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+func newHostKeyStore() *hostKeyStore {
+	return &hostKeyStore{keys: map[string]string{}}
+}
+
+// pinnedHostKey returns an ssh.HostKeyCallback implementing
+// trust-on-first-connect against the fingerprint pinned for address.
+func (s *hostKeyStore) pinnedHostKey(address string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		sum := sha256.Sum256(key.Marshal())
+		fingerprint := fmt.Sprintf("%x", sum)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		pinned, ok := s.keys[address]
+		if !ok {
+			s.keys[address] = fingerprint
+			return nil
+		}
+		if pinned != fingerprint {
+			return fmt.Errorf("engine: host key for %s does not match pinned fingerprint", hostname)
+		}
+		return nil
+	}
+}
+
+// dialSSH opens an SSH connection to instance, authenticating as root
+// with signer and pinning instance's host key fingerprint in keys, for
+// use by the exec RunnerProvisioner.
+func dialSSH(ctx context.Context, instance *autoscaler.Server, signer ssh.Signer, keys *hostKeyStore) (execSession, error) {
+	config := &ssh.ClientConfig{
+		User:            "root",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: keys.pinnedHostKey(instance.Address),
+		Timeout:         dialTimeout,
+	}
+
+	type result struct {
+		client *ssh.Client
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		client, err := ssh.Dial("tcp", fmt.Sprintf("%s:22", instance.Address), config)
+		done <- result{client, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-done:
+		if res.err != nil {
+			return nil, res.err
+		}
+		return &sshSession{client: res.client}, nil
+	}
+}
+
+// run executes cmd over the session, returning ctx.Err() if ctx is
+// done before the command finishes so an unreachable or hung VM
+// cannot block the caller indefinitely.
+func (s *sshSession) run(ctx context.Context, cmd string) error {
+	sess, err := s.client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sess.Run(cmd)
+	}()
+
+	select {
+	case <-ctx.Done():
+		sess.Close()
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
+
+func (s *sshSession) Close() error {
+	return s.client.Close()
+}
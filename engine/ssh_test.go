@@ -0,0 +1,149 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net"
+	"testing"
+
+	"github.com/drone/autoscaler"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func testSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate test key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("cannot build test signer: %v", err)
+	}
+	return signer
+}
+
+func TestHostKeyStorePinsOnFirstConnect(t *testing.T) {
+	store := newHostKeyStore()
+	key := testSigner(t).PublicKey()
+
+	if err := store.pinnedHostKey("10.0.0.1")("10.0.0.1:22", nil, key); err != nil {
+		t.Fatalf("pinnedHostKey() on first connect = %v, want nil", err)
+	}
+	if store.keys["10.0.0.1"] == "" {
+		t.Fatalf("pinnedHostKey() did not pin a fingerprint for 10.0.0.1")
+	}
+}
+
+func TestHostKeyStoreAcceptsSameKeyOnLaterConnect(t *testing.T) {
+	store := newHostKeyStore()
+	key := testSigner(t).PublicKey()
+	callback := store.pinnedHostKey("10.0.0.1")
+
+	if err := callback("10.0.0.1:22", nil, key); err != nil {
+		t.Fatalf("first connect: %v", err)
+	}
+	if err := callback("10.0.0.1:22", nil, key); err != nil {
+		t.Fatalf("second connect with the same key = %v, want nil", err)
+	}
+}
+
+func TestHostKeyStoreRejectsChangedKey(t *testing.T) {
+	store := newHostKeyStore()
+	callback := store.pinnedHostKey("10.0.0.1")
+
+	if err := callback("10.0.0.1:22", nil, testSigner(t).PublicKey()); err != nil {
+		t.Fatalf("first connect: %v", err)
+	}
+	if err := callback("10.0.0.1:22", nil, testSigner(t).PublicKey()); err == nil {
+		t.Fatalf("pinnedHostKey() with a different key = nil error, want mismatch error")
+	}
+}
+
+// startTestSSHServer runs a minimal SSH server on loopback that accepts
+// any client authentication and immediately closes each session, so
+// dialSSH can be exercised against a real connection.
+func startTestSSHServer(t *testing.T) (addr string) {
+	t.Helper()
+
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(testSigner(t))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				defer sconn.Close()
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+				}
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestDialSSHConnectsAndPinsHostKey(t *testing.T) {
+	host, _, err := net.SplitHostPort(startTestSSHServer(t))
+	if err != nil {
+		t.Fatalf("cannot split test server address: %v", err)
+	}
+	instance := &autoscaler.Server{Address: host}
+	keys := newHostKeyStore()
+
+	session, err := dialSSH(context.Background(), instance, testSigner(t), keys)
+	if err != nil {
+		t.Fatalf("dialSSH() error = %v", err)
+	}
+	defer session.Close()
+
+	if keys.keys[host] == "" {
+		t.Fatalf("dialSSH() did not pin a host key fingerprint for %s", host)
+	}
+}
+
+func TestDialSSHRejectsMismatchedPinnedHostKey(t *testing.T) {
+	host, _, err := net.SplitHostPort(startTestSSHServer(t))
+	if err != nil {
+		t.Fatalf("cannot split test server address: %v", err)
+	}
+	instance := &autoscaler.Server{Address: host}
+	keys := newHostKeyStore()
+	keys.keys[host] = "not-the-real-fingerprint"
+
+	if _, err := dialSSH(context.Background(), instance, testSigner(t), keys); err == nil {
+		t.Fatalf("dialSSH() with a mismatched pinned fingerprint = nil error, want an error")
+	}
+}
+
+func TestDialSSHReturnsContextError(t *testing.T) {
+	instance := &autoscaler.Server{Address: "10.255.255.1"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := dialSSH(ctx, instance, testSigner(t), newHostKeyStore()); err != context.Canceled {
+		t.Fatalf("dialSSH() with a canceled context error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/drone/autoscaler"
+
+	dockerclient "docker.io/go-docker"
+)
+
+// dockerTLSPort is the Docker daemon port used when connecting over
+// TLS, matching the DOCKER_TLS_VERIFY / DOCKER_CERT_PATH convention
+// used by the Drone and Woodpecker agents.
+const dockerTLSPort = "2376"
+
+// dockerTLS holds the CA and the pool's client certificate material
+// used to dial a remote Docker daemon over mTLS; the same certificate
+// is used for every server in the pool. It is nil when the
+// pool does not have TLS configured, in which case the provisioner
+// falls back to the plain clientFunc dialer.
+type dockerTLS struct {
+	ca   []byte
+	cert []byte
+	key  []byte
+}
+
+// config builds a tls.Config from the certificate material, suitable
+// for dialing tcp://host:2376.
+func (d *dockerTLS) config() (*tls.Config, error) {
+	cert, err := tls.X509KeyPair(d.cert, d.key)
+	if err != nil {
+		return nil, fmt.Errorf("engine: cannot load docker client certificate: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(d.ca) {
+		return nil, fmt.Errorf("engine: cannot parse docker CA certificate")
+	}
+
+	return &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{cert},
+	}, nil
+}
+
+// dial returns a Docker client connected to instance over TLS using
+// d's certificate material.
+func (d *dockerTLS) dial(instance *autoscaler.Server) (*dockerclient.Client, error) {
+	tlsConfig, err := d.config()
+	if err != nil {
+		return nil, err
+	}
+	httpClient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+	host := fmt.Sprintf("tcp://%s:%s", instance.Address, dockerTLSPort)
+	return dockerclient.NewClient(host, "", httpClient, nil)
+}
+
+// GenerateServerCert issues a client certificate for instance, signed
+// by the given CA. It is exported for callers that want to mint a
+// distinct certificate per server; NewInstaller does not call it
+// itself, so a pool's servers currently all dial over mTLS with the
+// single shared cert/key pair passed in at startup rather than a
+// certificate unique to each instance.
+func GenerateServerCert(instance *autoscaler.Server, caCert *x509.Certificate, caKey *ecdsa.PrivateKey) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: instance.Name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * 365 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP(instance.Address)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// fingerprint returns the SHA-256 fingerprint of the client
+// certificate used to dial every server in the pool, logged by
+// dockerProvisioner.Provision so operators can audit which
+// certificate a connection was authenticated with from the installer
+// logs, without retaining the key material anywhere themselves.
+func (d *dockerTLS) fingerprint() string {
+	sum := sha256.Sum256(d.cert)
+	return fmt.Sprintf("%x", sum)
+}
@@ -0,0 +1,28 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+func TestDockerTLSFingerprint(t *testing.T) {
+	cert := []byte("-----BEGIN CERTIFICATE-----\nfake\n-----END CERTIFICATE-----\n")
+	d := &dockerTLS{cert: cert}
+
+	want := fmt.Sprintf("%x", sha256.Sum256(cert))
+	if got := d.fingerprint(); got != want {
+		t.Fatalf("fingerprint() = %s, want %s", got, want)
+	}
+}
+
+func TestDockerTLSFingerprintStableAcrossCalls(t *testing.T) {
+	d := &dockerTLS{cert: []byte("same bytes")}
+	if d.fingerprint() != d.fingerprint() {
+		t.Fatalf("fingerprint() is not deterministic for the same certificate bytes")
+	}
+}
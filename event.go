@@ -0,0 +1,45 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package autoscaler
+
+import (
+	"context"
+	"time"
+)
+
+// EventKind identifies the stage of a server's lifecycle an Event
+// describes.
+type EventKind string
+
+// Event kinds emitted by the installer, and the docker
+// RunnerProvisioner it drives, as a server is provisioned. No kinds
+// are defined for teardown, and the destroyer publishes none.
+const (
+	EventServerStaging   EventKind = "server.staging"
+	EventDockerReachable EventKind = "docker.reachable"
+	EventImagePulled     EventKind = "image.pulled"
+	EventContainerCreate EventKind = "container.created"
+	EventContainerStart  EventKind = "container.started"
+	EventInstallFailed   EventKind = "install.failed"
+)
+
+// Event describes a single lifecycle transition for a server.
+type Event struct {
+	Kind     EventKind
+	Server   *Server
+	Duration time.Duration
+
+	// Reason and Err are set on EventInstallFailed, describing why
+	// the transition did not complete.
+	Reason string
+	Err    error
+}
+
+// EventBus publishes lifecycle events so external systems (metrics,
+// chat alerts, audit logs) can observe server provisioning without
+// polling ServerStore.
+type EventBus interface {
+	Publish(ctx context.Context, event Event)
+}
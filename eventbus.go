@@ -0,0 +1,28 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package autoscaler
+
+import "context"
+
+// eventBus is the default EventBus: it fans each published Event out
+// to every subscriber on its own goroutine, so a slow or unreachable
+// sink (e.g. a webhook endpoint) cannot stall server provisioning.
+// It is safe for concurrent use.
+type eventBus struct {
+	subs []EventBus
+}
+
+// NewEventBus returns an in-memory EventBus that fans out published
+// events to the given sinks (e.g. a webhook.Sink). With no sinks it
+// simply discards events, so callers can wire it in unconditionally.
+func NewEventBus(sinks ...EventBus) EventBus {
+	return &eventBus{subs: sinks}
+}
+
+func (b *eventBus) Publish(ctx context.Context, event Event) {
+	for _, sub := range b.subs {
+		go sub.Publish(ctx, event)
+	}
+}
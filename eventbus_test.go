@@ -0,0 +1,63 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+package autoscaler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingSink struct {
+	mu     sync.Mutex
+	events []Event
+	done   chan struct{}
+}
+
+func newRecordingSink(want int) *recordingSink {
+	return &recordingSink{done: make(chan struct{}, want)}
+}
+
+func (s *recordingSink) Publish(ctx context.Context, event Event) {
+	s.mu.Lock()
+	s.events = append(s.events, event)
+	s.mu.Unlock()
+	s.done <- struct{}{}
+}
+
+func (s *recordingSink) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.done:
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for sink to receive event %d/%d", i+1, n)
+		}
+	}
+}
+
+func TestEventBusPublishFansOutToEverySink(t *testing.T) {
+	a := newRecordingSink(1)
+	b := newRecordingSink(1)
+	bus := NewEventBus(a, b)
+
+	bus.Publish(context.Background(), Event{Kind: EventServerStaging})
+
+	a.wait(t, 1)
+	b.wait(t, 1)
+
+	if len(a.events) != 1 || a.events[0].Kind != EventServerStaging {
+		t.Fatalf("sink a received %+v, want one EventServerStaging", a.events)
+	}
+	if len(b.events) != 1 || b.events[0].Kind != EventServerStaging {
+		t.Fatalf("sink b received %+v, want one EventServerStaging", b.events)
+	}
+}
+
+func TestEventBusPublishWithNoSinksDoesNotBlock(t *testing.T) {
+	bus := NewEventBus()
+	bus.Publish(context.Background(), Event{Kind: EventServerStaging})
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 Drone.IO Inc
+// Use of this software is governed by the Business Source License
+// that can be found in the LICENSE file.
+
+// Package webhook implements an autoscaler.EventBus sink that
+// forwards lifecycle events to an external URL as JSON POSTs, for
+// operators who want to wire server provisioning into metrics,
+// chat alerts or audit logs.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/drone/autoscaler"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Sink is an autoscaler.EventBus that POSTs every event it receives
+// to a configured URL as JSON.
+type Sink struct {
+	URL    string
+	Client *http.Client
+}
+
+// New returns a Sink that posts events to url.
+func New(url string) *Sink {
+	return &Sink{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// payload is the JSON body posted for every event.
+type payload struct {
+	Kind     autoscaler.EventKind `json:"kind"`
+	Server   *autoscaler.Server   `json:"server"`
+	Duration time.Duration        `json:"duration,omitempty"`
+	Reason   string               `json:"reason,omitempty"`
+	Error    string               `json:"error,omitempty"`
+}
+
+func (s *Sink) Publish(ctx context.Context, event autoscaler.Event) {
+	body := payload{
+		Kind:     event.Kind,
+		Server:   event.Server,
+		Duration: event.Duration,
+		Reason:   event.Reason,
+	}
+	if event.Err != nil {
+		body.Error = event.Err.Error()
+	}
+
+	buf, err := json.Marshal(body)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Msg("cannot marshal event payload")
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(buf))
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Msg("cannot create event webhook request")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.Client.Do(req)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Str("url", s.URL).
+			Msg("cannot deliver event webhook")
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		log.Ctx(ctx).Warn().
+			Str("url", s.URL).
+			Int("status", res.StatusCode).
+			Msg("event webhook rejected delivery")
+	}
+}